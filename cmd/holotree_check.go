@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/robocorp/rcc/common"
+	"github.com/robocorp/rcc/htfs"
+	"github.com/robocorp/rcc/pretty"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkReadData     bool
+	checkMetadataOnly bool
+	checkRepair       bool
+	checkAsJson       bool
+)
+
+var holotreeCheckCmd = &cobra.Command{
+	Use:     "check",
+	Aliases: []string{"verify"},
+	Short:   "Verify integrity of holotree library and catalogs.",
+	Long:    "Verify integrity of holotree library and catalogs: that every blob a catalog references exists, rehashes correctly, and matches its recorded metadata, and that the library has no orphan blobs left over from removed catalogs.",
+	Run: func(cmd *cobra.Command, args []string) {
+		library, err := htfs.New()
+		pretty.Guard(err == nil, 1, "%v", err)
+		report, err := library.Check(htfs.CheckOptions{
+			ReadData:     checkReadData,
+			MetadataOnly: checkMetadataOnly,
+			Repair:       checkRepair,
+		})
+		pretty.Guard(err == nil, 1, "%v", err)
+		if checkAsJson {
+			blob, err := json.MarshalIndent(report, "", "  ")
+			pretty.Guard(err == nil, 1, "%v", err)
+			common.Stdout("%s\n", blob)
+		} else {
+			common.Stdout("Catalogs checked: %d\n", len(report.Catalogs))
+			common.Stdout("Missing blobs:    %d\n", len(report.Missing))
+			common.Stdout("Corrupt blobs:    %d\n", len(report.Corrupt))
+			common.Stdout("Orphan blobs:     %d\n", len(report.Orphans))
+		}
+		if report.Dirty() {
+			pretty.Exit(1, "Holotree check found problems!")
+		}
+	},
+}
+
+func init() {
+	holotreeCheckCmd.Flags().BoolVarP(&checkReadData, "read-data", "", false, "Rehash every referenced blob instead of trusting its size and mode.")
+	holotreeCheckCmd.Flags().BoolVarP(&checkMetadataOnly, "metadata-only", "", false, "Only check presence, size, and mode of referenced blobs; skip rehashing.")
+	holotreeCheckCmd.Flags().BoolVarP(&checkRepair, "repair", "", false, "Quarantine corrupt blobs and mark affected catalogs unusable.")
+	holotreeCheckCmd.Flags().BoolVarP(&checkAsJson, "json", "", false, "Show report as JSON.")
+	holotreeCmd.AddCommand(holotreeCheckCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/robocorp/rcc/common"
+	"github.com/robocorp/rcc/htfs"
+	"github.com/robocorp/rcc/pretty"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	forgetKeepLast         int
+	forgetKeepWithin       string
+	forgetKeepUnusedWithin string
+	forgetKeepTags         []string
+	forgetCommit           bool
+)
+
+var holotreeForgetCmd = &cobra.Command{
+	Use:     "forget",
+	Aliases: []string{"prune"},
+	Short:   "Apply a retention policy to holotree catalogs and garbage collect unused blobs.",
+	Long:    "Apply a retention policy to holotree catalogs, removing the ones that fall outside of it, then garbage collect library blobs that no surviving catalog references. Shows a dry-run report unless --commit is given.",
+	Run: func(cmd *cobra.Command, args []string) {
+		policy := htfs.RetentionPolicy{
+			KeepLast: forgetKeepLast,
+			KeepTags: forgetKeepTags,
+			DryRun:   !forgetCommit,
+		}
+		if len(forgetKeepWithin) > 0 {
+			within, err := htfs.ParseRetentionDuration(forgetKeepWithin)
+			pretty.Guard(err == nil, 1, "%v", err)
+			policy.KeepWithin = within
+		}
+		if len(forgetKeepUnusedWithin) > 0 {
+			within, err := htfs.ParseRetentionDuration(forgetKeepUnusedWithin)
+			pretty.Guard(err == nil, 1, "%v", err)
+			policy.KeepUnusedWithin = within
+		}
+
+		library, err := htfs.New()
+		pretty.Guard(err == nil, 1, "%v", err)
+		report, err := library.Prune(policy)
+		pretty.Guard(err == nil, 1, "%v", err)
+
+		common.Stdout("Keeping %d catalog(s).\n", len(report.Kept))
+		common.Stdout("Forgetting %d catalog(s): %s\n", len(report.Forgot), strings.Join(report.Forgot, ", "))
+		if report.DryRun {
+			common.Stdout("Dry run only, nothing was removed. Pass --commit to apply.\n")
+			return
+		}
+		common.Stdout("Removed %d orphan blob(s).\n", len(report.Orphans))
+	},
+}
+
+func init() {
+	holotreeForgetCmd.Flags().IntVarP(&forgetKeepLast, "keep-last", "", 0, "Keep the N most recently used catalogs.")
+	holotreeForgetCmd.Flags().StringVarP(&forgetKeepWithin, "keep-within", "", "", "Keep catalogs used within this duration, e.g. 30d.")
+	holotreeForgetCmd.Flags().StringVarP(&forgetKeepUnusedWithin, "keep-unused-within", "", "", "Keep never-used catalogs younger than this duration, e.g. 30d.")
+	holotreeForgetCmd.Flags().StringSliceVarP(&forgetKeepTags, "keep-tag", "", []string{}, "Keep catalogs carrying one of these tags, regardless of age.")
+	holotreeForgetCmd.Flags().BoolVarP(&forgetCommit, "commit", "", false, "Actually remove catalogs and orphan blobs instead of just reporting.")
+	holotreeCmd.AddCommand(holotreeForgetCmd)
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/robocorp/rcc/htfs"
+	"github.com/robocorp/rcc/pretty"
+
+	"github.com/spf13/cobra"
+)
+
+var holotreeMountCmd = &cobra.Command{
+	Use:   "mount <blueprint-hash> <directory>",
+	Short: "Mount a holotree catalog read-only over FUSE.",
+	Long:  "Mount a holotree catalog read-only over FUSE, so its content can be browsed, diffed, or grepped without a full restore to disk. Requires an rcc binary built with -tags fuse.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		library, err := htfs.New()
+		pretty.Guard(err == nil, 1, "%v", err)
+		err = library.MountCatalog(args[0], args[1])
+		pretty.Guard(err == nil, 1, "%v", err)
+	},
+}
+
+func init() {
+	holotreeCmd.AddCommand(holotreeMountCmd)
+}
@@ -0,0 +1,263 @@
+package htfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/robocorp/rcc/anywork"
+	"github.com/robocorp/rcc/common"
+	"github.com/robocorp/rcc/fail"
+	"github.com/robocorp/rcc/pathlib"
+	"github.com/robocorp/rcc/set"
+)
+
+// CheckOptions controls how thorough `holotree check` is. By default,
+// every referenced blob's existence and recorded size/mode are
+// checked. ReadData additionally rehashes blob content; MetadataOnly
+// forces the rehash back off even if ReadData was also given, trading
+// thoroughness for speed. Repair marks every catalog with a missing or
+// corrupt entry unusable, and moves whole blobs that fail the rehash to
+// the quarantine; blobs that only fail the size/mode check, and chunked
+// files, are reported but not moved.
+type CheckOptions struct {
+	ReadData     bool
+	MetadataOnly bool
+	Repair       bool
+}
+
+func (it CheckOptions) rehash() bool {
+	return it.ReadData && !it.MetadataOnly
+}
+
+// CheckReport is the structured result of one `holotree check` run, fit
+// to be rendered as text or marshalled as JSON.
+type CheckReport struct {
+	Catalogs []string `json:"catalogs"`
+	Missing  []string `json:"missing"`
+	Corrupt  []string `json:"corrupt"`
+	Orphans  []string `json:"orphans"`
+}
+
+func (it *CheckReport) Dirty() bool {
+	return len(it.Missing) > 0 || len(it.Corrupt) > 0
+}
+
+func rehash(location string) (string, error) {
+	source, err := os.Open(location)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, source)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func metadataMatches(location string, file *File) bool {
+	stat, err := os.Stat(location)
+	if err != nil {
+		return false
+	}
+	if stat.Size() != file.Size() {
+		return false
+	}
+	return stat.Mode().Perm() == file.Mode().Perm()
+}
+
+// chunkedMetadataMatches is metadataMatches' equivalent for a chunked
+// file: every chunk the recipe lists must exist, and their sizes must
+// add up to what the recipe (and, transitively, the catalog) recorded.
+// Mode isn't checked here -- chunk files carry their own fixed mode,
+// unrelated to the original file's -- only content placement is.
+func chunkedMetadataMatches(recipe *Recipe, file *File) bool {
+	if recipe.Size != file.Size() {
+		return false
+	}
+	var total int64
+	for _, chunk := range recipe.Chunks {
+		stat, err := os.Stat(ChunkLocation(chunk))
+		if err != nil {
+			return false
+		}
+		total += stat.Size()
+	}
+	return total == recipe.Size
+}
+
+// rehashRecipe reassembles `recipe` through OpenRecipe and hashes the
+// result, the chunked equivalent of rehash for a whole blob.
+func rehashRecipe(recipe *Recipe) (string, error) {
+	reader, closer, err := OpenRecipe(recipe)
+	if err != nil {
+		return "", err
+	}
+	defer closer()
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func quarantine(location, digest string) {
+	target := filepath.Join(common.HololibLibraryLocation(), ".corrupt", digest)
+	pathlib.EnsureDirectoryExists(filepath.Dir(target))
+	os.Rename(location, target)
+}
+
+// libraryChunksRoot is where ChunkLocation fans chunk blobs out under,
+// used to tell a chunk blob apart from a whole-file one during orphan
+// detection (both happen to match the same four-level glob).
+func libraryChunksRoot() string {
+	return filepath.Join(common.HololibLibraryLocation(), "chunks")
+}
+
+// sidecarDigest strips a recipe/xattrs sidecar suffix off a library
+// file's base name, recovering the digest the sidecar belongs to, so
+// orphan detection (here and in Prune's GC) judges the sidecar by the
+// same referenced-ness as the file it describes rather than its own
+// literal (suffixed) name.
+func sidecarDigest(base string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(base, ".recipe"), ".xattrs")
+}
+
+// Check walks every catalog under the catalog directory and verifies
+// that its referenced content exists, that its on-disk size and mode
+// match what the catalog recorded, and, when requested, that its
+// content rehashes to the claimed digest. A chunked file (one with a
+// recipe sidecar) is verified through its chunks instead of a whole
+// blob, since RecordChunks reclaims the whole blob once chunking
+// happens. It finishes by diffing the set of blobs, chunks, and
+// sidecars actually on disk against everything referenced by a
+// surviving catalog, so leftovers are reported as orphans.
+func (it *hololib) Check(options CheckOptions) (report *CheckReport, err error) {
+	defer fail.Around(&err)
+
+	report = &CheckReport{}
+	referenced := make(map[string]bool)
+	referencedChunks := make(map[string]bool)
+	unusable := make(map[string]bool)
+	var mutex sync.Mutex
+
+	blueprintOf := make(map[string]string)
+
+	for _, name := range CatalogNames() {
+		name := name
+		report.Catalogs = append(report.Catalogs, name)
+		catalog := filepath.Join(common.HololibCatalogLocation(), name)
+		fs, err := NewRoot(".")
+		fail.On(err != nil, "Could not create root location -> %v.", err)
+		err = fs.LoadFrom(catalog)
+		if err != nil {
+			common.Debug("holotree check: catalog %q failed to load -> %v", name, err)
+			continue
+		}
+		blueprintOf[name] = fs.Blueprint
+
+		err = fs.AllFiles(func(file *File) error {
+			digest := file.Digest()
+			mutex.Lock()
+			referenced[digest] = true
+			mutex.Unlock()
+
+			if recipe, ok := LoadRecipe(digest); ok {
+				mutex.Lock()
+				for _, chunk := range recipe.Chunks {
+					referencedChunks[chunk] = true
+				}
+				mutex.Unlock()
+				if !chunkedMetadataMatches(recipe, file) {
+					mutex.Lock()
+					report.Missing = append(report.Missing, fmt.Sprintf("%s: %s", name, digest))
+					unusable[name] = true
+					mutex.Unlock()
+					return nil
+				}
+				if !options.rehash() {
+					return nil
+				}
+				anywork.Backlog(func() {
+					seen, err := rehashRecipe(recipe)
+					if err != nil || seen != digest {
+						mutex.Lock()
+						report.Corrupt = append(report.Corrupt, fmt.Sprintf("%s: %s", name, digest))
+						unusable[name] = true
+						mutex.Unlock()
+					}
+				})
+				return nil
+			}
+
+			location := it.ExactLocation(digest)
+			if !pathlib.IsFile(location) {
+				mutex.Lock()
+				report.Missing = append(report.Missing, fmt.Sprintf("%s: %s", name, digest))
+				unusable[name] = true
+				mutex.Unlock()
+				return nil
+			}
+			if !metadataMatches(location, file) {
+				mutex.Lock()
+				report.Corrupt = append(report.Corrupt, fmt.Sprintf("%s: %s", name, digest))
+				unusable[name] = true
+				mutex.Unlock()
+				return nil
+			}
+			if !options.rehash() {
+				return nil
+			}
+			anywork.Backlog(func() {
+				seen, err := rehash(location)
+				if err != nil || seen != digest {
+					mutex.Lock()
+					report.Corrupt = append(report.Corrupt, fmt.Sprintf("%s: %s", name, digest))
+					unusable[name] = true
+					mutex.Unlock()
+					if options.Repair {
+						quarantine(location, digest)
+					}
+				}
+			})
+			return nil
+		})
+		fail.On(err != nil, "Could not walk catalog %q -> %v", name, err)
+	}
+	anywork.Sync()
+
+	if options.Repair {
+		mutex.Lock()
+		for name, blueprint := range blueprintOf {
+			if unusable[name] {
+				it.queryCache[blueprint] = false
+			}
+		}
+		mutex.Unlock()
+	}
+
+	chunksRoot := libraryChunksRoot() + string(filepath.Separator)
+	for _, location := range pathlib.Glob(common.HololibLibraryLocation(), "*/*/*/*") {
+		if strings.HasPrefix(location, chunksRoot) {
+			if chunk := filepath.Base(location); !referencedChunks[chunk] {
+				report.Orphans = append(report.Orphans, chunk)
+			}
+			continue
+		}
+		digest := sidecarDigest(filepath.Base(location))
+		if !referenced[digest] {
+			report.Orphans = append(report.Orphans, digest)
+		}
+	}
+	report.Missing = set.Set(report.Missing)
+	report.Corrupt = set.Set(report.Corrupt)
+	report.Orphans = set.Set(report.Orphans)
+	return report, nil
+}
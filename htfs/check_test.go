@@ -0,0 +1,68 @@
+package htfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRehashMatchesContentDigest(t *testing.T) {
+	directory := t.TempDir()
+	location := filepath.Join(directory, "blob")
+	content := []byte("some blob content for rehashing")
+	if err := os.WriteFile(location, content, 0o644); err != nil {
+		t.Fatalf("could not write fixture: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	expected := hex.EncodeToString(sum[:])
+
+	seen, err := rehash(location)
+	if err != nil {
+		t.Fatalf("rehash failed: %v", err)
+	}
+	if seen != expected {
+		t.Fatalf("expected %s, got %s", expected, seen)
+	}
+}
+
+func TestRehashMissingFile(t *testing.T) {
+	_, err := rehash(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestSidecarDigestStripsKnownSuffixes(t *testing.T) {
+	cases := map[string]string{
+		"deadbeef.recipe": "deadbeef",
+		"deadbeef.xattrs": "deadbeef",
+		"deadbeef":        "deadbeef",
+	}
+	for input, expected := range cases {
+		if got := sidecarDigest(input); got != expected {
+			t.Fatalf("sidecarDigest(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestCheckOptionsRehashGate(t *testing.T) {
+	cases := []struct {
+		name     string
+		options  CheckOptions
+		expected bool
+	}{
+		{"default", CheckOptions{}, false},
+		{"read-data", CheckOptions{ReadData: true}, true},
+		{"metadata-only wins over read-data", CheckOptions{ReadData: true, MetadataOnly: true}, false},
+		{"metadata-only alone", CheckOptions{MetadataOnly: true}, false},
+	}
+	for _, entry := range cases {
+		t.Run(entry.name, func(t *testing.T) {
+			if got := entry.options.rehash(); got != entry.expected {
+				t.Fatalf("expected %v, got %v", entry.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package htfs
+
+import (
+	"io"
+)
+
+// FastCDC style content-defined chunking. Boundaries are picked by a
+// 64-bit gear hash rolled over the byte stream, so two files that share
+// a long common run of bytes will cut into identical chunks even when
+// that run starts at different offsets.
+const (
+	chunkMinSize = 2 * 1024
+	chunkAvgSize = 16 * 1024
+	chunkMaxSize = 64 * 1024
+
+	chunkMaskSmall = uint64(1<<15) - 1 // pushes the average up while below chunkAvgSize
+	chunkMaskLarge = uint64(1<<13) - 1 // pulls the average down once past chunkAvgSize
+)
+
+var gearTable = makeGearTable()
+
+func makeGearTable() [256]uint64 {
+	var table [256]uint64
+	seed := uint64(0x9e3779b97f4a7c15)
+	for index := range table {
+		seed ^= seed << 13
+		seed ^= seed >> 7
+		seed ^= seed << 17
+		seed += uint64(index)
+		table[index] = seed
+	}
+	return table
+}
+
+// Chunk is one content-defined slice of a file, identified by its own
+// digest so identical chunks across unrelated files collapse to a
+// single blob in the library.
+type Chunk struct {
+	Digest string
+	Size   int64
+}
+
+// ChunkCallback receives every chunk cut from the stream together with
+// its raw bytes, in order. Returning an error aborts chunking.
+type ChunkCallback func(chunk Chunk, content []byte) error
+
+// ChunkReader splits `source` at content-defined boundaries and invokes
+// `sink` for every chunk found, in order. Small inputs (below
+// chunkMinSize) come back as a single chunk so whole-file mode keeps
+// working for the common case of small files.
+func ChunkReader(source io.Reader, sink ChunkCallback) (total int64, err error) {
+	buffer := make([]byte, 0, chunkMaxSize)
+	chunk := make([]byte, chunkMaxSize)
+
+	flush := func(size int) error {
+		if size == 0 {
+			return nil
+		}
+		content := append([]byte{}, buffer[:size]...)
+		digest := digestOf(content)
+		total += int64(size)
+		return sink(Chunk{Digest: digest, Size: int64(size)}, content)
+	}
+
+	var hash uint64
+	for {
+		count, readErr := source.Read(chunk)
+		for offset := 0; offset < count; offset++ {
+			buffer = append(buffer, chunk[offset])
+			hash = (hash << 1) + gearTable[chunk[offset]]
+			size := len(buffer)
+			if size < chunkMinSize {
+				continue
+			}
+			mask := chunkMaskLarge
+			if size < chunkAvgSize {
+				mask = chunkMaskSmall
+			}
+			if size >= chunkMaxSize || (hash&mask) == 0 {
+				if err = flush(size); err != nil {
+					return total, err
+				}
+				buffer = buffer[:0]
+				hash = 0
+			}
+		}
+		if readErr == io.EOF {
+			if err = flush(len(buffer)); err != nil {
+				return total, err
+			}
+			return total, nil
+		}
+		if readErr != nil {
+			return total, readErr
+		}
+	}
+}
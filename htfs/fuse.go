@@ -0,0 +1,211 @@
+//go:build fuse
+
+package htfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+
+	"github.com/robocorp/rcc/common"
+)
+
+// treeNode is an in-memory directory tree built from the catalog's
+// flat file list (via the already-established fs.AllFiles callback),
+// so the FUSE layer doesn't need to invent any new lookup/listing
+// methods on *Root.
+type treeNode struct {
+	name     string
+	file     *File
+	children map[string]*treeNode
+}
+
+func newTreeNode(name string) *treeNode {
+	return &treeNode{name: name, children: make(map[string]*treeNode)}
+}
+
+func (it *treeNode) isDir() bool {
+	return len(it.children) > 0 || it.file == nil
+}
+
+func (it *treeNode) child(name string) *treeNode {
+	found, ok := it.children[name]
+	if !ok {
+		found = newTreeNode(name)
+		it.children[name] = found
+	}
+	return found
+}
+
+func buildTree(root *Root) (*treeNode, error) {
+	top := newTreeNode(".")
+	err := root.AllFiles(func(file *File) error {
+		parts := strings.Split(path.Clean(filepathToSlash(file.Path())), "/")
+		cursor := top
+		for _, part := range parts[:len(parts)-1] {
+			cursor = cursor.child(part)
+		}
+		leaf := cursor.child(parts[len(parts)-1])
+		leaf.file = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+func filepathToSlash(value string) string {
+	return strings.ReplaceAll(value, "\\", "/")
+}
+
+type catalogFS struct {
+	library *hololib
+	top     *treeNode
+}
+
+func (it *catalogFS) Root() (fs.Node, error) {
+	return &catalogDir{library: it.library, node: it.top}, nil
+}
+
+type catalogDir struct {
+	library *hololib
+	node    *treeNode
+}
+
+func (it *catalogDir) Attr(ctx context.Context, attr *fuse.Attr) error {
+	attr.Mode = os.ModeDir | 0o555
+	return nil
+}
+
+func (it *catalogDir) Lookup(ctx context.Context, name string) (fs.Node, error) {
+	child, ok := it.node.children[name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	if child.isDir() {
+		return &catalogDir{library: it.library, node: child}, nil
+	}
+	return &catalogFile{library: it.library, file: child.file}, nil
+}
+
+func (it *catalogDir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	result := make([]fuse.Dirent, 0, len(it.node.children))
+	for name, child := range it.node.children {
+		kind := fuse.DT_File
+		if child.isDir() {
+			kind = fuse.DT_Dir
+		}
+		result = append(result, fuse.Dirent{Name: name, Type: kind})
+	}
+	return result, nil
+}
+
+type catalogFile struct {
+	library *hololib
+	file    *File
+
+	mtx      sync.Mutex
+	reader   io.Reader
+	closer   Closer
+	position int64
+}
+
+func (it *catalogFile) Attr(ctx context.Context, attr *fuse.Attr) error {
+	attr.Mode = 0o444
+	attr.Size = uint64(it.file.Size())
+	return nil
+}
+
+// Open lets each FUSE open reuse the same underlying stream across
+// repeated Read calls instead of re-reading the blob/recipe from the
+// start on every call.
+func (it *catalogFile) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	resp.Flags |= fuse.OpenKeepCache
+	return it, nil
+}
+
+// reopen (re)starts the underlying blob/recipe stream from position 0.
+// Call with mtx held.
+func (it *catalogFile) reopen() error {
+	if it.closer != nil {
+		it.closer()
+		it.closer = nil
+		it.reader = nil
+	}
+	reader, closer, err := it.library.Open(it.file.Digest())
+	if err != nil {
+		return err
+	}
+	it.reader = reader
+	it.closer = closer
+	it.position = 0
+	return nil
+}
+
+// Read streams the requested window straight from the library instead
+// of loading the whole file into memory, so opening a multi-gigabyte
+// blob (the ONNX models/embedded Pythons chunking targets) doesn't
+// risk OOMing the mount. The underlying reader is forward-only, so a
+// backward seek reopens it and discards up to the requested offset.
+func (it *catalogFile) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+
+	if it.reader == nil || req.Offset < it.position {
+		if err := it.reopen(); err != nil {
+			return err
+		}
+	}
+	if skip := req.Offset - it.position; skip > 0 {
+		moved, err := io.CopyN(io.Discard, it.reader, skip)
+		it.position += moved
+		if err != nil {
+			return err
+		}
+	}
+
+	buffer := make([]byte, req.Size)
+	count, err := io.ReadFull(it.reader, buffer)
+	it.position += int64(count)
+	resp.Data = buffer[:count]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
+
+func (it *catalogFile) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+	if it.closer != nil {
+		err := it.closer()
+		it.closer = nil
+		it.reader = nil
+		return err
+	}
+	return nil
+}
+
+func mountCatalogImpl(library *hololib, root *Root, mountpoint string) error {
+	top, err := buildTree(root)
+	if err != nil {
+		return err
+	}
+
+	connection, err := fuse.Mount(mountpoint, fuse.ReadOnly(), fuse.FSName("rcc-holotree"))
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	common.Log("Serving catalog read-only at %q. Unmount to stop.", mountpoint)
+	return fs.Serve(connection, &catalogFS{library: library, top: top})
+}
@@ -0,0 +1,11 @@
+//go:build !fuse
+
+package htfs
+
+import (
+	"fmt"
+)
+
+func mountCatalogImpl(library *hololib, root *Root, mountpoint string) error {
+	return fmt.Errorf("this %q binary was not built with FUSE support (build with -tags fuse)", "rcc")
+}
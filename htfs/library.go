@@ -79,6 +79,7 @@ type Library interface {
 	TargetDir([]byte, []byte, []byte) (string, error)
 	Restore([]byte, []byte, []byte) (string, error)
 	RestoreTo([]byte, string, string, string, bool) (string, error)
+	SetRestoreObserver(RestoreObserver)
 }
 
 type MutableLibrary interface {
@@ -93,15 +94,33 @@ type MutableLibrary interface {
 	Stage() string
 	CatalogPath(string) string
 	WriteIdentity([]byte) error
+	Check(CheckOptions) (*CheckReport, error)
+	Prune(RetentionPolicy) (*PruneReport, error)
+	MountCatalog(string, string) error
 }
 
 type hololib struct {
 	identity   uint64
 	basedir    string
 	queryCache map[string]bool
+	observer   RestoreObserver
+}
+
+func (it *hololib) SetRestoreObserver(observer RestoreObserver) {
+	if observer == nil {
+		observer = NoopObserver
+	}
+	it.observer = observer
 }
 
 func (it *hololib) Open(digest string) (readable io.Reader, closer Closer, err error) {
+	// Recipe presence alone decides, not the current Chunked() toggle
+	// state: RecordChunks reclaims the whole blob once a recipe is
+	// saved, so a file chunked while the toggle was on must still open
+	// through its recipe even if the toggle is later switched off.
+	if recipe, ok := LoadRecipe(digest); ok {
+		return OpenRecipe(recipe)
+	}
 	return delegateOpen(it, digest, Compress())
 }
 
@@ -273,10 +292,33 @@ func (it *hololib) Record(blueprint []byte) error {
 	common.Timeline("holotree lift done")
 	defer common.Timeline("- new %d/%d (duplicate: %d, links: %d)", score.dirty, score.total, score.duplicate, score.links)
 	common.Debug("Holotree new workload: %d/%d\n", score.dirty, score.total)
+	if err != nil {
+		return err
+	}
+	if XattrsEnabled() {
+		common.TimelineBegin("holotree xattr capture start %q", catalog)
+		err = RecordXattrs(fs)
+		common.TimelineEnd()
+		if err != nil {
+			return err
+		}
+	}
+	if Chunked() {
+		common.TimelineBegin("holotree chunk migration start %q", catalog)
+		err = RecordChunks(fs)
+		common.TimelineEnd()
+	}
 	return err
 }
 
 func CatalogName(key string) string {
+	return fmt.Sprintf("%sv13.%s", key, common.Platform())
+}
+
+// LegacyCatalogName is the pre-xattr (v12) catalog name. Catalogs
+// written before xattr support was added keep loading
+// read-only under their old name instead of being orphaned outright.
+func LegacyCatalogName(key string) string {
 	return fmt.Sprintf("%sv12.%s", key, common.Platform())
 }
 
@@ -284,6 +326,34 @@ func (it *hololib) CatalogPath(key string) string {
 	return filepath.Join(common.HololibCatalogLocation(), CatalogName(key))
 }
 
+func (it *hololib) legacyCatalogPath(key string) string {
+	return filepath.Join(common.HololibCatalogLocation(), LegacyCatalogName(key))
+}
+
+// MountCatalog serves the catalog identified by `key` read-only over
+// FUSE at `mountpoint`, so a blueprint can be browsed or diffed without
+// a full RestoreTo. Actual mounting is gated behind the `fuse` build
+// tag so non-fuse builds (notably Windows) still compile.
+func (it *hololib) MountCatalog(key, mountpoint string) (err error) {
+	defer fail.Around(&err)
+
+	catalog := it.CatalogPath(key)
+	fail.On(!pathlib.IsFile(catalog), "No catalog found for blueprint %q.", key)
+	fs, err := NewRoot(".")
+	fail.On(err != nil, "Could not create root location -> %v", err)
+	err = fs.LoadFrom(catalog)
+	fail.On(err != nil, "Could not load catalog %q -> %v", catalog, err)
+
+	lockfile := UserHolotreeLockfile()
+	completed := pathlib.LockWaitMessage(lockfile, "Serialized holotree mount [holotree base lock]")
+	locker, err := pathlib.Locker(lockfile, 30000, common.SharedHolotree)
+	completed()
+	fail.On(err != nil, "Could not get lock for %s. Quiting.", lockfile)
+	defer locker.Release()
+
+	return mountCatalogImpl(it, fs, mountpoint)
+}
+
 func (it *hololib) ValidateBlueprint(blueprint []byte) error {
 	return nil
 }
@@ -306,7 +376,12 @@ func (it *hololib) queryBlueprint(key string) bool {
 	common.Timeline("holotree blueprint query")
 	catalog := it.CatalogPath(key)
 	if !pathlib.IsFile(catalog) {
-		return false
+		legacy := it.legacyCatalogPath(key)
+		if !pathlib.IsFile(legacy) {
+			return false
+		}
+		common.Debug("Holotree found legacy v12 catalog for %q, reading it read-only.", key)
+		catalog = legacy
 	}
 	tempdir := filepath.Join(common.ProductTemp(), key)
 	shadow, err := NewRoot(tempdir)
@@ -330,9 +405,11 @@ func (it *hololib) queryBlueprint(key string) bool {
 
 func CatalogNames() []string {
 	result := make([]string, 0, 10)
-	for _, catalog := range pathlib.Glob(common.HololibCatalogLocation(), "[0-9a-f]*v12.*") {
-		if filepath.Ext(catalog) != ".info" {
-			result = append(result, filepath.Base(catalog))
+	for _, pattern := range []string{"[0-9a-f]*v13.*", "[0-9a-f]*v12.*"} {
+		for _, catalog := range pathlib.Glob(common.HololibCatalogLocation(), pattern) {
+			if filepath.Ext(catalog) != ".info" {
+				result = append(result, filepath.Base(catalog))
+			}
 		}
 	}
 	return set.Set(result)
@@ -416,15 +493,27 @@ func (it *hololib) RestoreTo(blueprint []byte, label, controller, space string,
 	}
 	common.Timeline("mode: %s", mode)
 	common.Debug("Holotree operating mode is: %s", mode)
+
+	restoring := newRestoreIndex()
+	common.TimelineBegin("holotree resume scan start")
+	err = ResumeRestore(fs, targetdir, currentstate, it.observer, restoring)
+	common.TimelineEnd()
+	fail.On(err != nil, "Failed to scan %s for resumable files -> %v", targetdir, err)
+
 	err = fs.Relocate(targetdir)
 	fail.On(err != nil, "Failed to relocate %s -> %v", targetdir, err)
 	common.TimelineBegin("holotree make branches start")
 	err = fs.Treetop(MakeBranches)
 	common.TimelineEnd()
 	fail.On(err != nil, "Failed to make branches -> %v", err)
+	common.TimelineBegin("holotree materialize start")
+	err = MaterializeAll(it, fs, targetdir, currentstate, restoring)
+	common.TimelineEnd()
+	fail.On(err != nil, "Failed to materialize %s -> %v", targetdir, err)
 	score := &stats{}
 	common.TimelineBegin("holotree restore start")
 	err = fs.AllDirs(RestoreDirectory(it, fs, currentstate, score))
+	FinishRestore(restoring)
 	fail.On(err != nil, "Failed to restore directories -> %v", err)
 	common.TimelineEnd()
 	defer common.Timeline("- dirty %d/%d (duplicate: %d, links: %d)", score.dirty, score.total, score.duplicate, score.links)
@@ -472,5 +561,6 @@ func New() (MutableLibrary, error) {
 		identity:   common.Sipit([]byte(identity)),
 		basedir:    basedir,
 		queryCache: make(map[string]bool),
+		observer:   NoopObserver,
 	}, nil
 }
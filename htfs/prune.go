@@ -0,0 +1,242 @@
+package htfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robocorp/rcc/common"
+	"github.com/robocorp/rcc/fail"
+	"github.com/robocorp/rcc/pathlib"
+	"github.com/robocorp/rcc/pretty"
+)
+
+// RetentionPolicy mirrors restic's `forget` knobs: keep the N most
+// recently used catalogs, keep everything touched within a duration,
+// keep catalogs that have never been used within a (usually longer)
+// grace duration, and always keep catalogs carrying one of the given
+// tags regardless of age.
+type RetentionPolicy struct {
+	KeepLast         int
+	KeepWithin       time.Duration
+	KeepUnusedWithin time.Duration
+	KeepTags         []string
+	DryRun           bool
+}
+
+// PruneReport lists what a Prune call forgot (or would forget, for a
+// dry run) and what the following library GC swept up.
+type PruneReport struct {
+	Kept    []string `json:"kept"`
+	Forgot  []string `json:"forgot"`
+	Orphans []string `json:"orphans"`
+	DryRun  bool     `json:"dry_run"`
+}
+
+// ParseRetentionDuration extends time.ParseDuration with a trailing
+// "d" unit for days, since retention windows are naturally expressed
+// that way ("30d") rather than as hours.
+func ParseRetentionDuration(text string) (time.Duration, error) {
+	if strings.HasSuffix(text, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(text, "d"))
+		if err != nil {
+			return 0, fail.Wrap(err, "Invalid day count in %q", text)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(text)
+}
+
+func catalogTag(catalog string) string {
+	sidecar := catalog + ".info"
+	content, err := os.ReadFile(sidecar)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// blueprintKey recovers the bare blueprint hash a catalog name was
+// built from by CatalogName/LegacyCatalogName, e.g.
+// "<key>v13.linux64" -> "<key>". touchUsedHash records usage markers
+// under that bare key, not the full catalog filename.
+func blueprintKey(name string) string {
+	for _, marker := range []string{"v13.", "v12."} {
+		if index := strings.Index(name, marker); index >= 0 {
+			return name[:index]
+		}
+	}
+	return name
+}
+
+func catalogUsed(key string) (time.Time, bool) {
+	found := pathlib.Glob(common.HololibUsageLocation(), key+".*")
+	if len(found) == 0 {
+		return time.Time{}, false
+	}
+	when, err := pathlib.Modtime(found[0])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return when, true
+}
+
+type catalogEntry struct {
+	name     string
+	modified time.Time
+	used     time.Time
+	hasUsage bool
+	tag      string
+}
+
+func (it *hololib) catalogEntries() ([]catalogEntry, error) {
+	names := CatalogNames()
+	entries := make([]catalogEntry, 0, len(names))
+	for _, name := range names {
+		catalog := filepath.Join(common.HololibCatalogLocation(), name)
+		modified, err := pathlib.Modtime(catalog)
+		if err != nil {
+			continue
+		}
+		used, hasUsage := catalogUsed(blueprintKey(name))
+		entries = append(entries, catalogEntry{
+			name:     name,
+			modified: modified,
+			used:     used,
+			hasUsage: hasUsage,
+			tag:      catalogTag(catalog),
+		})
+	}
+	return entries, nil
+}
+
+func (entry catalogEntry) keptByTag(tags []string) bool {
+	for _, tag := range tags {
+		if tag == entry.tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune classifies every catalog against `policy`, forgets the ones
+// that fall outside it (through the existing Remove flow), and then
+// runs a library GC that deletes any blob no surviving catalog still
+// references. With policy.DryRun set, nothing is removed -- the report
+// just shows what would happen.
+func (it *hololib) Prune(policy RetentionPolicy) (report *PruneReport, err error) {
+	defer fail.Around(&err)
+
+	common.TimelineBegin("holotree prune start")
+	defer common.TimelineEnd()
+
+	lockfile := UserHolotreeLockfile()
+	completed := pathlib.LockWaitMessage(lockfile, "Serialized holotree prune [holotree base lock]")
+	locker, err := pathlib.Locker(lockfile, 30000, common.SharedHolotree)
+	completed()
+	fail.On(err != nil, "Could not get lock for %s. Quiting.", lockfile)
+	defer locker.Release()
+
+	entries, err := it.catalogEntries()
+	fail.On(err != nil, "Could not list catalogs -> %v", err)
+
+	byRecency := append([]catalogEntry{}, entries...)
+	for index := range byRecency {
+		for swap := index + 1; swap < len(byRecency); swap++ {
+			if byRecency[swap].modified.After(byRecency[index].modified) {
+				byRecency[index], byRecency[swap] = byRecency[swap], byRecency[index]
+			}
+		}
+	}
+
+	now := time.Now()
+	keep := make(map[string]bool)
+	for index, entry := range byRecency {
+		if policy.KeepLast > 0 && index < policy.KeepLast {
+			keep[entry.name] = true
+		}
+		if policy.KeepWithin > 0 && now.Sub(entry.modified) <= policy.KeepWithin {
+			keep[entry.name] = true
+		}
+		if policy.KeepUnusedWithin > 0 && !entry.hasUsage && now.Sub(entry.modified) <= policy.KeepUnusedWithin {
+			keep[entry.name] = true
+		}
+		if entry.keptByTag(policy.KeepTags) {
+			keep[entry.name] = true
+		}
+	}
+
+	report = &PruneReport{DryRun: policy.DryRun}
+	forget := make([]string, 0)
+	for _, entry := range entries {
+		if keep[entry.name] {
+			report.Kept = append(report.Kept, entry.name)
+			continue
+		}
+		forget = append(forget, entry.name)
+	}
+	report.Forgot = forget
+
+	if policy.DryRun {
+		pretty.Note("Dry run: would forget %d catalog(s), keep %d.", len(forget), len(report.Kept))
+		return report, nil
+	}
+
+	if len(forget) > 0 {
+		err = it.Remove(forget)
+		fail.On(err != nil, "Could not forget catalogs -> %v", err)
+	}
+
+	referenced := make(map[string]bool)
+	referencedChunks := make(map[string]bool)
+	for _, name := range CatalogNames() {
+		catalog := filepath.Join(common.HololibCatalogLocation(), name)
+		fs, err := NewRoot(".")
+		fail.On(err != nil, "Could not create root location -> %v", err)
+		if err = fs.LoadFrom(catalog); err != nil {
+			continue
+		}
+		fs.AllFiles(func(file *File) error {
+			digest := file.Digest()
+			referenced[digest] = true
+			if recipe, ok := LoadRecipe(digest); ok {
+				for _, chunk := range recipe.Chunks {
+					referencedChunks[chunk] = true
+				}
+			}
+			return nil
+		})
+	}
+	// A chunked file's whole blob is already gone by the time it gets
+	// here (RecordChunks reclaims it), and its chunks plus recipe/xattrs
+	// sidecars all happen to match the same four-level library glob as
+	// a whole blob would, so they need their own referenced check
+	// instead of being swept as orphans alongside it.
+	chunksRoot := libraryChunksRoot() + string(filepath.Separator)
+	for _, location := range pathlib.Glob(common.HololibLibraryLocation(), "*/*/*/*") {
+		if strings.HasPrefix(location, chunksRoot) {
+			if chunk := filepath.Base(location); !referencedChunks[chunk] {
+				report.Orphans = append(report.Orphans, chunk)
+				err = os.Remove(location)
+				fail.On(err != nil, "Could not remove orphan chunk %q -> %v", chunk, err)
+			}
+			continue
+		}
+		digest := sidecarDigest(filepath.Base(location))
+		if referenced[digest] {
+			continue
+		}
+		report.Orphans = append(report.Orphans, digest)
+		err = os.Remove(location)
+		fail.On(err != nil, "Could not remove orphan blob %q -> %v", digest, err)
+	}
+
+	return report, nil
+}
+
+func (it *PruneReport) String() string {
+	return fmt.Sprintf("kept: %d, forgot: %d, orphans removed: %d", len(it.Kept), len(it.Forgot), len(it.Orphans))
+}
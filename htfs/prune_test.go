@@ -0,0 +1,46 @@
+package htfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetentionDurationDays(t *testing.T) {
+	result, err := ParseRetentionDuration("30d")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 30*24*time.Hour {
+		t.Fatalf("expected 30 days, got %v", result)
+	}
+}
+
+func TestParseRetentionDurationFallsBackToStdlib(t *testing.T) {
+	result, err := ParseRetentionDuration("72h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 72*time.Hour {
+		t.Fatalf("expected 72h, got %v", result)
+	}
+}
+
+func TestParseRetentionDurationRejectsGarbageDayCount(t *testing.T) {
+	_, err := ParseRetentionDuration("xyzd")
+	if err == nil {
+		t.Fatal("expected an error for a non-numeric day count")
+	}
+}
+
+func TestBlueprintKeyStripsCatalogSuffix(t *testing.T) {
+	cases := map[string]string{
+		"deadbeefv13.linux64": "deadbeef",
+		"deadbeefv12.linux64": "deadbeef",
+		"alreadybare":         "alreadybare",
+	}
+	for input, expected := range cases {
+		if got := blueprintKey(input); got != expected {
+			t.Fatalf("blueprintKey(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
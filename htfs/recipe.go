@@ -0,0 +1,249 @@
+package htfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/robocorp/rcc/common"
+	"github.com/robocorp/rcc/fail"
+	"github.com/robocorp/rcc/pathlib"
+)
+
+// Recipe is the per-file "how to rebuild me" record used by chunked
+// catalog entries: the ordered chunk digests plus enough metadata to
+// verify the reassembled result without re-chunking it. Xattrs carries
+// along whatever extended attributes ReadXattrs captured on the
+// original file, so AssembleFile can replay them; OpenRecipe only
+// streams the content and does not apply them.
+type Recipe struct {
+	Chunks []string          `json:"chunks" yaml:"chunks"`
+	Size   int64             `json:"size" yaml:"size"`
+	Digest string            `json:"digest" yaml:"digest"`
+	Xattrs map[string][]byte `json:"xattrs,omitempty" yaml:"xattrs,omitempty"`
+}
+
+// RecipeLocation returns where a file's recipe sidecar lives, next to
+// its whole-blob location, so presence of the sidecar is the "optional
+// recipe pointer" that tells Open/RestoreTo to stream-assemble from
+// chunks instead of reading the (absent) whole blob.
+func RecipeLocation(digest string) string {
+	return ExactDefaultLocation(digest) + ".recipe"
+}
+
+// SaveRecipe persists `recipe` as the sidecar for `digest`.
+func SaveRecipe(digest string, recipe *Recipe) (err error) {
+	defer fail.Around(&err)
+
+	blob, err := json.Marshal(recipe)
+	fail.On(err != nil, "Could not marshal recipe for %q -> %v", digest, err)
+	return pathlib.WriteFile(RecipeLocation(digest), blob, 0o644)
+}
+
+// LoadRecipe reads back the recipe sidecar for `digest`, if one exists.
+func LoadRecipe(digest string) (*Recipe, bool) {
+	location := RecipeLocation(digest)
+	if !pathlib.IsFile(location) {
+		return nil, false
+	}
+	blob, err := os.ReadFile(location)
+	if err != nil {
+		return nil, false
+	}
+	recipe := &Recipe{}
+	if err = json.Unmarshal(blob, recipe); err != nil {
+		return nil, false
+	}
+	return recipe, true
+}
+
+// recipeReader streams a recipe's chunks back as a single io.Reader, so
+// chunked files can be opened exactly like whole-blob ones.
+type recipeReader struct {
+	recipe *Recipe
+	index  int
+	source *os.File
+}
+
+func (it *recipeReader) Read(buffer []byte) (int, error) {
+	for {
+		if it.source == nil {
+			if it.index >= len(it.recipe.Chunks) {
+				return 0, io.EOF
+			}
+			source, err := os.Open(ChunkLocation(it.recipe.Chunks[it.index]))
+			if err != nil {
+				return 0, err
+			}
+			it.source = source
+			it.index++
+		}
+		count, err := it.source.Read(buffer)
+		if err == io.EOF {
+			it.source.Close()
+			it.source = nil
+			if count > 0 {
+				return count, nil
+			}
+			continue
+		}
+		return count, err
+	}
+}
+
+// OpenRecipe returns a reader that transparently reassembles `recipe`'s
+// chunks on the fly, plus a Closer that releases whatever chunk file is
+// currently open.
+func OpenRecipe(recipe *Recipe) (io.Reader, Closer, error) {
+	reader := &recipeReader{recipe: recipe}
+	closer := func() error {
+		if reader.source != nil {
+			return reader.source.Close()
+		}
+		return nil
+	}
+	return reader, closer, nil
+}
+
+func digestOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// ChunkLocation returns where a content-defined chunk lives in the
+// library, mirroring the fan-out used by ExactDefaultLocation for
+// whole-file blobs.
+func ChunkLocation(digest string) string {
+	return filepath.Join(common.HololibLibraryLocation(), "chunks", digest[:2], digest[2:4], digest)
+}
+
+// HololibChunkMarker is the opt-in marker file; its presence turns on
+// chunked storage for new recordings, same toggle shape as Compress().
+func HololibChunkMarker() string {
+	return filepath.Join(common.HololibLocation(), "chunked.yes")
+}
+
+// Chunked reports whether content-defined chunking is enabled for this
+// hololib. Off by default, so existing catalogs keep using the
+// whole-blob fast path until an operator opts in.
+func Chunked() bool {
+	return pathlib.IsFile(HololibChunkMarker())
+}
+
+// BuildRecipe chunks `source`, writes every not-yet-seen chunk into the
+// library, and returns the recipe needed to reassemble it later. Files
+// smaller than chunkMinSize produce a single-chunk recipe, which keeps
+// the whole-file fast path intact for the common small-file case.
+func BuildRecipe(source io.Reader) (recipe *Recipe, err error) {
+	defer fail.Around(&err)
+
+	recipe = &Recipe{Chunks: make([]string, 0, 4)}
+	hasher := sha256.New()
+	total, err := ChunkReader(source, func(chunk Chunk, content []byte) error {
+		hasher.Write(content)
+		recipe.Chunks = append(recipe.Chunks, chunk.Digest)
+		location := ChunkLocation(chunk.Digest)
+		if pathlib.IsFile(location) {
+			return nil
+		}
+		return pathlib.WriteFile(location, content, 0o644)
+	})
+	fail.On(err != nil, "Could not chunk content -> %v", err)
+	recipe.Size = total
+	recipe.Digest = hex.EncodeToString(hasher.Sum(nil))
+	return recipe, nil
+}
+
+// AssembleFile streams `recipe` back into `target`, verifying the
+// whole-file digest before the caller relies on the result, and
+// replays any xattrs the recipe carries unless xattr handling is
+// switched off.
+func AssembleFile(recipe *Recipe, target string) (err error) {
+	defer fail.Around(&err)
+
+	sink, err := pathlib.Create(target)
+	fail.On(err != nil, "Could not create %q -> %v", target, err)
+	defer sink.Close()
+
+	hasher := sha256.New()
+	writer := io.MultiWriter(sink, hasher)
+	for _, digest := range recipe.Chunks {
+		location := ChunkLocation(digest)
+		source, err := os.Open(location)
+		fail.On(err != nil, "Missing chunk %q for %q -> %v", digest, target, err)
+		_, err = io.Copy(writer, source)
+		source.Close()
+		fail.On(err != nil, "Could not assemble %q -> %v", target, err)
+	}
+	fail.On(hex.EncodeToString(hasher.Sum(nil)) != recipe.Digest, "Assembled content for %q does not match recipe digest!", target)
+	if XattrsEnabled() && len(recipe.Xattrs) > 0 {
+		WriteXattrs(target, recipe.Xattrs)
+	}
+	return nil
+}
+
+// RecordChunks migrates every regular file in `fs` whose whole blob is
+// at or above the minimum chunk size into chunked storage: it chunks
+// the existing blob, stores a recipe sidecar next to it, and then
+// removes the whole blob, since Open reads straight from the recipe
+// once one exists. Smaller files and files that already have a
+// matching recipe are left alone, so repeated Record calls only do the
+// work once per file, and chunking never leaves both a whole blob and
+// its chunks occupying space at the same time.
+func RecordChunks(fs *Root) (err error) {
+	defer fail.Around(&err)
+
+	return fs.AllFiles(func(file *File) error {
+		digest := file.Digest()
+		if file.Size() < chunkMinSize {
+			return nil
+		}
+		if _, ok := LoadRecipe(digest); ok {
+			return nil
+		}
+		location := ExactDefaultLocation(digest)
+		if !pathlib.IsFile(location) {
+			return nil
+		}
+		source, err := os.Open(location)
+		fail.On(err != nil, "Could not open %q for chunking -> %v", location, err)
+
+		recipe, buildErr := BuildRecipe(source)
+		source.Close()
+		fail.On(buildErr != nil, "Could not build recipe for %q -> %v", location, buildErr)
+		fail.On(recipe.Digest != digest, "Chunked recipe digest mismatch for %q: expected %s, got %s", location, digest, recipe.Digest)
+
+		if XattrsEnabled() {
+			xattrs, err := ReadXattrs(location)
+			if err == nil {
+				recipe.Xattrs = xattrs
+			}
+		}
+		err = SaveRecipe(digest, recipe)
+		fail.On(err != nil, "Could not save recipe for %q -> %v", location, err)
+
+		err = os.Remove(location)
+		fail.On(err != nil, "Chunked %q but could not reclaim whole blob -> %v", location, err)
+		return nil
+	})
+}
+
+// VerifyRecipe re-chunks `source` and reports whether the result's
+// whole-file digest matches `recipe`'s, checking the content itself
+// instead of trusting stale metadata.
+func VerifyRecipe(recipe *Recipe, source io.Reader) bool {
+	var buffer bytes.Buffer
+	_, err := io.Copy(&buffer, source)
+	if err != nil {
+		return false
+	}
+	rebuilt, err := BuildRecipe(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		return false
+	}
+	return rebuilt.Digest == recipe.Digest
+}
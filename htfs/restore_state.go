@@ -0,0 +1,276 @@
+package htfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/robocorp/rcc/fail"
+	"github.com/robocorp/rcc/pathlib"
+)
+
+// RestoreObserver lets a caller (CLI progress bar, a hosting UI) watch
+// a RestoreTo call materialize files without coupling htfs to any
+// particular rendering. All three methods may be called from multiple
+// goroutines concurrently, once per file in flight.
+type RestoreObserver interface {
+	Start(path string, size int64)
+	Progress(path string, delta int64)
+	Finish(path string, err error)
+}
+
+type noopObserver struct{}
+
+func (noopObserver) Start(path string, size int64)   {}
+func (noopObserver) Progress(path string, delta int64) {}
+func (noopObserver) Finish(path string, err error)    {}
+
+// NoopObserver is the default RestoreObserver: it does nothing, so
+// RestoreTo callers that don't care about progress pay nothing for it.
+var NoopObserver RestoreObserver = noopObserver{}
+
+// sharedPullerState tracks one file's journey from library blob to
+// restored target: how many bytes have landed in the temp file, and
+// the terminal error (if any) once the worker is done with it. It is
+// shared between the worker goroutine doing the copy and whatever is
+// polling for progress.
+type sharedPullerState struct {
+	mtx sync.Mutex
+
+	digest   string
+	target   string
+	temp     string
+	size     int64
+	copied   int64
+	err      error
+	done     chan struct{}
+	observer RestoreObserver
+}
+
+func newSharedPullerState(digest, target string, size int64, observer RestoreObserver) *sharedPullerState {
+	if observer == nil {
+		observer = NoopObserver
+	}
+	state := &sharedPullerState{
+		digest:   digest,
+		target:   target,
+		temp:     fmt.Sprintf("%s.partial", target),
+		size:     size,
+		done:     make(chan struct{}),
+		observer: observer,
+	}
+	observer.Start(target, size)
+	return state
+}
+
+func (it *sharedPullerState) addProgress(delta int64) {
+	it.mtx.Lock()
+	it.copied += delta
+	it.mtx.Unlock()
+	it.observer.Progress(it.target, delta)
+}
+
+func (it *sharedPullerState) finish(err error) {
+	it.mtx.Lock()
+	it.err = err
+	it.mtx.Unlock()
+	it.observer.Finish(it.target, err)
+	close(it.done)
+}
+
+// restoreIndex keeps one sharedPullerState per relative path for the
+// lifetime of a single RestoreTo call, so progress can be looked up by
+// path instead of threaded through every call site.
+type restoreIndex struct {
+	mtx   sync.Mutex
+	files map[string]*sharedPullerState
+}
+
+func newRestoreIndex() *restoreIndex {
+	return &restoreIndex{files: make(map[string]*sharedPullerState)}
+}
+
+func (it *restoreIndex) track(relativepath string, state *sharedPullerState) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+	it.files[relativepath] = state
+}
+
+func (it *restoreIndex) lookup(relativepath string) (*sharedPullerState, bool) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+	state, ok := it.files[relativepath]
+	return state, ok
+}
+
+func (it *restoreIndex) each(callback func(relativepath string, state *sharedPullerState)) {
+	it.mtx.Lock()
+	defer it.mtx.Unlock()
+	for relativepath, state := range it.files {
+		callback(relativepath, state)
+	}
+}
+
+// materializedOk reports whether `target` already holds content
+// matching `digest`, which lets a restart of RestoreTo skip files a
+// previous, interrupted run already finished.
+func materializedOk(target, digest string) bool {
+	if !pathlib.IsFile(target) {
+		return false
+	}
+	seen, err := rehash(target)
+	return err == nil && seen == digest
+}
+
+// ResumeRestore is what makes a crashed RestoreTo resumable: for every
+// file the catalog `fs` references whose target under `targetdir`
+// already verifies against its digest, it marks that path as unchanged
+// in `currentstate` (so the restore worker hardlinks/skips it instead
+// of recopying) and reports it to `observer`/`index` as already
+// finished. A half-written ".partial" temp file left behind by a
+// previous, interrupted MaterializeFile call is simply ignored here and
+// overwritten by the next attempt. Every file not already listed in
+// `currentstate` also gets a tracked sharedPullerState up front, so the
+// observer sees a Start for it before any copying begins.
+func ResumeRestore(fs *Root, targetdir string, currentstate map[string]string, observer RestoreObserver, index *restoreIndex) error {
+	return fs.AllFiles(func(file *File) error {
+		relative := file.Path()
+		digest := file.Digest()
+		if _, already := currentstate[relative]; already {
+			return nil
+		}
+		target := filepath.Join(targetdir, relative)
+		state := newSharedPullerState(digest, target, file.Size(), observer)
+		index.track(relative, state)
+		if materializedOk(target, digest) {
+			currentstate[relative] = digest
+			state.finish(nil)
+		}
+		return nil
+	})
+}
+
+// MaterializeAll is the actual content-copy worker: for every file
+// ResumeRestore didn't already find verified on disk, it fetches the
+// content from `library` and writes it through MaterializeFile, which
+// is what gives a real restore genuine per-file progress reporting and
+// crash-resumable ".partial" temp files instead of only the pre-scan
+// exercising that machinery. currentstate is updated as each file
+// lands, so the directory pass that follows (fs.AllDirs(RestoreDirectory(...)),
+// which still owns directory structure, hardlink fast paths across
+// spaces, and removing files that no longer belong) finds every file
+// already matching and has no content left to copy.
+func MaterializeAll(library *hololib, fs *Root, targetdir string, currentstate map[string]string, index *restoreIndex) error {
+	return fs.AllFiles(func(file *File) error {
+		relative := file.Path()
+		digest := file.Digest()
+		if seen, already := currentstate[relative]; already && seen == digest {
+			return nil
+		}
+		target := filepath.Join(targetdir, relative)
+		state, tracked := index.lookup(relative)
+		if !tracked {
+			state = newSharedPullerState(digest, target, file.Size(), library.observer)
+			index.track(relative, state)
+		}
+		reader, closer, err := library.Open(digest)
+		if err != nil {
+			state.finish(err)
+			return err
+		}
+		err = MaterializeFile(state, reader, digest)
+		closer()
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(target, file.Mode()); err != nil {
+			return err
+		}
+		currentstate[relative] = digest
+		return nil
+	})
+}
+
+// FinishRestore closes out every tracked sharedPullerState that has not
+// finished yet (neither verified by the ResumeRestore pre-scan nor
+// finished by MaterializeAll), verifying the target on disk against its
+// digest so the observer still gets an accurate Finish call for it.
+func FinishRestore(index *restoreIndex) {
+	index.each(func(relativepath string, state *sharedPullerState) {
+		select {
+		case <-state.done:
+			return
+		default:
+		}
+		if materializedOk(state.target, state.digest) {
+			state.finish(nil)
+			return
+		}
+		state.finish(fmt.Errorf("restore did not produce a verified %q", state.target))
+	})
+}
+
+// MaterializeFile copies the content for `digest` from `reader`, until
+// EOF, into a temp file next to `state.target`, reporting progress
+// through `state`, and only renames the temp file into place once the
+// copied content's digest matches. A half-written temp file left behind
+// by a crash is simply overwritten on the next attempt.
+func MaterializeFile(state *sharedPullerState, reader io.Reader, digest string) (err error) {
+	defer func() {
+		state.finish(err)
+	}()
+	defer fail.Around(&err)
+
+	if materializedOk(state.target, digest) {
+		return nil
+	}
+
+	sink, err := pathlib.Create(state.temp)
+	fail.On(err != nil, "Could not create temp file %q -> %v", state.temp, err)
+
+	buffer := make([]byte, 64*1024)
+	for {
+		count, readErr := reader.Read(buffer)
+		if count > 0 {
+			_, writeErr := sink.Write(buffer[:count])
+			fail.On(writeErr != nil, "Could not write temp file %q -> %v", state.temp, writeErr)
+			state.addProgress(int64(count))
+		}
+		if readErr == io.EOF {
+			break
+		}
+		fail.On(readErr != nil, "Could not read content for %q -> %v", digest, readErr)
+	}
+	sink.Close()
+
+	seen, err := rehash(state.temp)
+	fail.On(err != nil, "Could not verify temp file %q -> %v", state.temp, err)
+	fail.On(seen != digest, "Digest mismatch for %q: expected %s, got %s", state.target, digest, seen)
+
+	err = os.Rename(state.temp, state.target)
+	fail.On(err != nil, "Could not rename %q to %q -> %v", state.temp, state.target, err)
+
+	if XattrsEnabled() {
+		if xattrs, ok := xattrsFor(digest); ok {
+			WriteXattrs(state.target, xattrs)
+		}
+	}
+	return nil
+}
+
+// xattrsFor looks up whatever extended attributes were captured for
+// `digest`, preferring the generic per-digest sidecar RecordXattrs
+// writes, and falling back to a chunked file's own recipe (RecordChunks
+// stores xattrs there too, and it is the only copy for a file whose
+// whole blob was already chunked away before its sidecar was written).
+func xattrsFor(digest string) (map[string][]byte, bool) {
+	if xattrs, ok := LoadXattrsSidecar(digest); ok {
+		return xattrs, true
+	}
+	if recipe, ok := LoadRecipe(digest); ok && len(recipe.Xattrs) > 0 {
+		return recipe.Xattrs, true
+	}
+	return nil, false
+}
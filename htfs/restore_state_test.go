@@ -0,0 +1,92 @@
+package htfs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type recordingObserver struct {
+	starts   []string
+	finishes []string
+}
+
+func (it *recordingObserver) Start(path string, size int64)   { it.starts = append(it.starts, path) }
+func (it *recordingObserver) Progress(path string, delta int64) {}
+func (it *recordingObserver) Finish(path string, err error)   { it.finishes = append(it.finishes, path) }
+
+func TestMaterializedOkRejectsMismatchedContent(t *testing.T) {
+	directory := t.TempDir()
+	target := filepath.Join(directory, "target")
+	if err := os.WriteFile(target, []byte("wrong content"), 0o644); err != nil {
+		t.Fatalf("could not write fixture: %v", err)
+	}
+	if materializedOk(target, digestOf([]byte("expected content"))) {
+		t.Fatal("expected mismatch to be rejected")
+	}
+}
+
+func TestMaterializedOkAcceptsVerifiedContent(t *testing.T) {
+	directory := t.TempDir()
+	target := filepath.Join(directory, "target")
+	content := []byte("expected content")
+	if err := os.WriteFile(target, content, 0o644); err != nil {
+		t.Fatalf("could not write fixture: %v", err)
+	}
+	if !materializedOk(target, digestOf(content)) {
+		t.Fatal("expected verified content to be accepted")
+	}
+}
+
+func TestMaterializeFileSkipsAlreadyVerifiedTarget(t *testing.T) {
+	directory := t.TempDir()
+	target := filepath.Join(directory, "target")
+	content := []byte("already there")
+	if err := os.WriteFile(target, content, 0o644); err != nil {
+		t.Fatalf("could not write fixture: %v", err)
+	}
+	observer := &recordingObserver{}
+	state := newSharedPullerState(digestOf(content), target, int64(len(content)), observer)
+
+	err := MaterializeFile(state, nil, digestOf(content))
+	if err != nil {
+		t.Fatalf("expected no-op success, got: %v", err)
+	}
+	if len(observer.finishes) != 1 || observer.finishes[0] != target {
+		t.Fatalf("expected exactly one Finish call for %q, got %v", target, observer.finishes)
+	}
+}
+
+func TestMaterializeFileLeavesNoPartialOnSuccess(t *testing.T) {
+	directory := t.TempDir()
+	target := filepath.Join(directory, "target")
+	content := []byte("fresh content to copy")
+	observer := &recordingObserver{}
+	state := newSharedPullerState(digestOf(content), target, int64(len(content)), observer)
+
+	err := MaterializeFile(state, &byteReader{content}, digestOf(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(state.temp); !os.IsNotExist(err) {
+		t.Fatalf("expected temp file %q to be gone after a successful rename", state.temp)
+	}
+	seen, err := os.ReadFile(target)
+	if err != nil || string(seen) != string(content) {
+		t.Fatalf("expected target to hold the copied content, got %q, err %v", seen, err)
+	}
+}
+
+type byteReader struct {
+	content []byte
+}
+
+func (it *byteReader) Read(buffer []byte) (int, error) {
+	if len(it.content) == 0 {
+		return 0, io.EOF
+	}
+	count := copy(buffer, it.content)
+	it.content = it.content[count:]
+	return count, nil
+}
@@ -0,0 +1,91 @@
+package htfs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/robocorp/rcc/common"
+	"github.com/robocorp/rcc/fail"
+	"github.com/robocorp/rcc/pathlib"
+)
+
+// NoXattrsMarker is the opt-out marker file; its presence turns xattr
+// capture/replay off even on platforms that support it. BSD/macOS file
+// flags are not captured or replayed at all.
+func NoXattrsMarker() string {
+	return filepath.Join(common.HololibLocation(), "noxattrs.yes")
+}
+
+// XattrsEnabled reports whether extended attributes should be captured
+// during Record and replayed during RestoreTo.
+func XattrsEnabled() bool {
+	return !pathlib.IsFile(NoXattrsMarker())
+}
+
+// XattrsLocation returns where a file's captured-xattrs sidecar lives,
+// next to its whole-blob location, mirroring RecipeLocation's sidecar
+// convention. Unlike Recipe.Xattrs (only ever populated for chunked
+// files), this sidecar is written for every file regardless of
+// chunking, so xattr preservation also works on a default install.
+func XattrsLocation(digest string) string {
+	return ExactDefaultLocation(digest) + ".xattrs"
+}
+
+// SaveXattrsSidecar persists `xattrs` as the sidecar for `digest`. A
+// file with no extended attributes writes no sidecar at all.
+func SaveXattrsSidecar(digest string, xattrs map[string][]byte) (err error) {
+	defer fail.Around(&err)
+
+	if len(xattrs) == 0 {
+		return nil
+	}
+	blob, err := json.Marshal(xattrs)
+	fail.On(err != nil, "Could not marshal xattrs for %q -> %v", digest, err)
+	return pathlib.WriteFile(XattrsLocation(digest), blob, 0o644)
+}
+
+// LoadXattrsSidecar reads back the xattrs sidecar for `digest`, if one
+// was captured.
+func LoadXattrsSidecar(digest string) (map[string][]byte, bool) {
+	location := XattrsLocation(digest)
+	if !pathlib.IsFile(location) {
+		return nil, false
+	}
+	blob, err := os.ReadFile(location)
+	if err != nil {
+		return nil, false
+	}
+	xattrs := make(map[string][]byte)
+	if err = json.Unmarshal(blob, &xattrs); err != nil {
+		return nil, false
+	}
+	return xattrs, true
+}
+
+// RecordXattrs captures extended attributes for every regular file in
+// `fs` straight from its stored library blob, independent of whether
+// chunking is on. This is what makes xattr preservation work on a
+// default install: Recipe.Xattrs (see RecordChunks) only ever exists
+// for chunked files, so the generic sidecar here is what RestoreTo's
+// MaterializeFile reads first, and the only source for unchunked files.
+// Record runs this before RecordChunks, while whole blobs still exist.
+func RecordXattrs(fs *Root) (err error) {
+	defer fail.Around(&err)
+
+	return fs.AllFiles(func(file *File) error {
+		digest := file.Digest()
+		if _, ok := LoadXattrsSidecar(digest); ok {
+			return nil
+		}
+		location := ExactDefaultLocation(digest)
+		if !pathlib.IsFile(location) {
+			return nil
+		}
+		xattrs, err := ReadXattrs(location)
+		if err != nil || len(xattrs) == 0 {
+			return nil
+		}
+		return SaveXattrsSidecar(digest, xattrs)
+	})
+}
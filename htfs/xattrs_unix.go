@@ -0,0 +1,64 @@
+//go:build !windows
+
+package htfs
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// ReadXattrs captures every extended attribute set on `path`, e.g.
+// macOS quarantine/code-signing `com.apple.*` attributes or Linux
+// `security.capability`/`user.*` ones, so RestoreTo can put them back
+// after materializing the file content.
+func ReadXattrs(path string) (map[string][]byte, error) {
+	names, err := unix.Listxattr(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if names <= 0 {
+		return nil, nil
+	}
+	namebuf := make([]byte, names)
+	names, err = unix.Listxattr(path, namebuf)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte)
+	for _, name := range splitNames(namebuf[:names]) {
+		size, err := unix.Getxattr(path, name, nil)
+		if err != nil || size <= 0 {
+			continue
+		}
+		value := make([]byte, size)
+		size, err = unix.Getxattr(path, name, value)
+		if err != nil {
+			continue
+		}
+		result[name] = value[:size]
+	}
+	return result, nil
+}
+
+// WriteXattrs re-applies extended attributes captured by ReadXattrs.
+// Best-effort: a filesystem that doesn't support a given attribute is
+// not treated as a fatal restore error.
+func WriteXattrs(path string, xattrs map[string][]byte) error {
+	for name, value := range xattrs {
+		unix.Setxattr(path, name, value, 0)
+	}
+	return nil
+}
+
+func splitNames(namebuf []byte) []string {
+	result := make([]string, 0, 4)
+	start := 0
+	for index, char := range namebuf {
+		if char == 0 {
+			if index > start {
+				result = append(result, string(namebuf[start:index]))
+			}
+			start = index + 1
+		}
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+
+package htfs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestReadWriteXattrsRoundtrip(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "sample")
+	if err := os.WriteFile(path, []byte("content"), 0o644); err != nil {
+		t.Fatalf("could not write fixture: %v", err)
+	}
+
+	if err := unix.Setxattr(path, "user.rcc.test", []byte("hello"), 0); err != nil {
+		t.Skipf("filesystem does not support user xattrs here: %v", err)
+	}
+
+	captured, err := ReadXattrs(path)
+	if err != nil {
+		t.Fatalf("ReadXattrs failed: %v", err)
+	}
+	if !bytes.Equal(captured["user.rcc.test"], []byte("hello")) {
+		t.Fatalf("expected captured xattr %q, got %q", "hello", captured["user.rcc.test"])
+	}
+
+	target := filepath.Join(directory, "target")
+	if err := os.WriteFile(target, []byte("content"), 0o644); err != nil {
+		t.Fatalf("could not write target fixture: %v", err)
+	}
+	if err := WriteXattrs(target, captured); err != nil {
+		t.Fatalf("WriteXattrs failed: %v", err)
+	}
+	replayed, err := ReadXattrs(target)
+	if err != nil {
+		t.Fatalf("ReadXattrs on target failed: %v", err)
+	}
+	if !bytes.Equal(replayed["user.rcc.test"], []byte("hello")) {
+		t.Fatalf("expected replayed xattr %q, got %q", "hello", replayed["user.rcc.test"])
+	}
+}
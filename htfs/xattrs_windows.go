@@ -0,0 +1,15 @@
+//go:build windows
+
+package htfs
+
+// ReadXattrs is a no-op on Windows: there is no POSIX xattr equivalent
+// wired up here, so catalogs written on Windows simply carry no
+// extended attributes.
+func ReadXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// WriteXattrs is a no-op on Windows, matching ReadXattrs.
+func WriteXattrs(path string, xattrs map[string][]byte) error {
+	return nil
+}